@@ -0,0 +1,254 @@
+package bcryptx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	argon2Threads = 4
+
+	argon2MinMemoryKiB = 8 * 1024
+	argon2MaxTime      = 1 << 20
+)
+
+// argon2idHasher implements Hasher using golang.org/x/crypto/argon2. Memory
+// is tuned first, up to maxMemoryMiB; once that ceiling is reached, time is
+// tuned instead, per the argon2 recommendation to prefer more memory over
+// more time when both are available.
+type argon2idHasher struct {
+	mu           sync.RWMutex
+	maxMemoryMiB int
+	quickM       uint32
+	quickT       uint32
+	strongM      uint32
+	strongT      uint32
+}
+
+// newArgon2idHasher returns an argon2idHasher bounded by maxMemoryMiB, or
+// DefaultMaxMemoryMiB if zero.
+func newArgon2idHasher(maxMemoryMiB int) *argon2idHasher {
+	if maxMemoryMiB == 0 {
+		maxMemoryMiB = DefaultMaxMemoryMiB
+	}
+	return &argon2idHasher{maxMemoryMiB: maxMemoryMiB}
+}
+
+// GenerateQuick returns a hash produced using the tuned quick-tier cost.
+func (h *argon2idHasher) GenerateQuick(pass string) (string, error) {
+	h.mu.RLock()
+	m, t := h.quickM, h.quickT
+	h.mu.RUnlock()
+	return h.generate(pass, m, t)
+}
+
+// GenerateStrong returns a hash produced using the tuned strong-tier cost.
+func (h *argon2idHasher) GenerateStrong(pass string) (string, error) {
+	h.mu.RLock()
+	m, t := h.strongM, h.strongT
+	h.mu.RUnlock()
+	return h.generate(pass, m, t)
+}
+
+func (h *argon2idHasher) generate(pass string, m, t uint32) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(pass), salt, t, m, argon2Threads, argon2KeyLen)
+	return encodeArgon2id(m, t, argon2Threads, salt, key), nil
+}
+
+// Compare returns an error if hash and pass do not match.
+func (h *argon2idHasher) Compare(hash, pass string) error {
+	m, t, p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	cmp := argon2.IDKey([]byte(pass), salt, t, m, p, uint32(len(key)))
+	if !constantTimeEqual(cmp, key) {
+		return errors.New("bcryptx: argon2id hashedPassword does not match password")
+	}
+	return nil
+}
+
+// Cost returns a value combining the memory and time encoded in hash,
+// comparable against QuickCost and StrongCost.
+func (h *argon2idHasher) Cost(hash string) (int, error) {
+	m, t, _, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return 0, err
+	}
+	return argon2Cost(m, t), nil
+}
+
+// QuickCost returns the cost set by the most recent Tune.
+func (h *argon2idHasher) QuickCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return argon2Cost(h.quickM, h.quickT)
+}
+
+// StrongCost returns the cost set by the most recent Tune.
+func (h *argon2idHasher) StrongCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return argon2Cost(h.strongM, h.strongT)
+}
+
+// TuneParams returns the tuned [memory, time] pair for each tier.
+func (h *argon2idHasher) TuneParams() (quick, strong []int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return []int{int(h.quickM), int(h.quickT)}, []int{int(h.strongM), int(h.strongT)}
+}
+
+// SetTuneParams restores quickM/quickT and strongM/strongT from the
+// [memory, time] pairs in quick and strong.
+func (h *argon2idHasher) SetTuneParams(quick, strong []int) {
+	h.mu.Lock()
+	h.quickM, h.quickT = uint32(quick[0]), uint32(quick[1])
+	h.strongM, h.strongT = uint32(strong[0]), uint32(strong[1])
+	h.mu.Unlock()
+}
+
+// Prefix returns the encoded-hash prefix used by this package's argon2id
+// encoding.
+func (h *argon2idHasher) Prefix() string {
+	return argon2idPrefix
+}
+
+// Algorithm returns AlgorithmArgon2id.
+func (h *argon2idHasher) Algorithm() Algorithm {
+	return AlgorithmArgon2id
+}
+
+// Tune sets the quick- and strong-tier memory/time parameters based on the
+// provided max times. Memory is doubled from min(argon2MinMemoryKiB, maxM)
+// until another doubling would exceed maxMemoryMiB; if a max time still
+// isn't hit, time is doubled instead with memory held there.
+func (h *argon2idHasher) Tune(quickMax, strongMax time.Duration) error {
+	maxM := uint32(h.maxMemoryMiB * 1024)
+
+	floor := uint32(argon2MinMemoryKiB)
+	if maxM < floor {
+		floor = maxM
+	}
+
+	var qm, qt, sm, st uint32
+	m, t := floor, uint32(1)
+	for {
+		t1 := time.Now()
+		argon2.IDKey([]byte(testStr), []byte(testStr), t, m, argon2Threads, argon2KeyLen)
+		d := time.Since(t1)
+
+		if qm == 0 && d > quickMax {
+			qm, qt = prevArgon2Params(m, t, floor)
+		}
+		if sm == 0 && d > strongMax {
+			sm, st = prevArgon2Params(m, t, floor)
+		}
+
+		if sm != 0 {
+			break
+		}
+
+		if m*2 <= maxM {
+			m *= 2
+		} else if t < argon2MaxTime {
+			t *= 2
+		} else {
+			break
+		}
+	}
+
+	if qm == 0 {
+		qm, qt = m, t
+	}
+	if sm == 0 {
+		sm, st = m, t
+	}
+
+	h.mu.Lock()
+	h.quickM, h.quickT = qm, qt
+	h.strongM, h.strongT = sm, st
+	h.mu.Unlock()
+
+	return nil
+}
+
+// prevArgon2Params undoes the last doubling step applied to m or t, never
+// taking m below floor.
+func prevArgon2Params(m, t, floor uint32) (uint32, uint32) {
+	if t > 1 {
+		return m, t / 2
+	}
+	if m > floor {
+		return m / 2, t
+	}
+	return m, t
+}
+
+// argon2Cost combines memory and time into a single comparable value.
+func argon2Cost(m, t uint32) int {
+	return log2(int(m))*10 + int(t)
+}
+
+func encodeArgon2id(m, t uint32, p uint8, salt, key []byte) string {
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%sm=%d,t=%d,p=%d$%s$%s", argon2idPrefix, m, t, p,
+		enc.EncodeToString(salt), enc.EncodeToString(key))
+}
+
+func decodeArgon2id(hash string) (m, t uint32, p uint8, salt, key []byte, err error) {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	parts := strings.Split(strings.TrimPrefix(hash, argon2idPrefix), "$")
+	if len(parts) != 3 {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	params := strings.Split(parts[0], ",")
+	if len(params) != 3 {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	mi, errM := parseArgon2Param(params[0], "m=")
+	ti, errT := parseArgon2Param(params[1], "t=")
+	pi, errP := parseArgon2Param(params[2], "p=")
+	if errM != nil || errT != nil || errP != nil {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	enc := base64.RawStdEncoding
+	salt, errSalt := enc.DecodeString(parts[1])
+	key, errKey := enc.DecodeString(parts[2])
+	if errSalt != nil || errKey != nil {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	return uint32(mi), uint32(ti), uint8(pi), salt, key, nil
+}
+
+func parseArgon2Param(s, prefix string) (int, error) {
+	if !strings.HasPrefix(s, prefix) {
+		return 0, ErrMalformed
+	}
+	return strconv.Atoi(strings.TrimPrefix(s, prefix))
+}
@@ -0,0 +1,157 @@
+package bcryptx
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minCost    = bcrypt.MinCost
+	maxCost    = bcrypt.MaxCost
+	interpTime = time.Millisecond * 50
+	testStr    = "#!PnutBudr"
+
+	// bcryptPrefix matches the major version common to every bcrypt minor
+	// version ($2a$, $2b$, $2x$, $2y$), since CompareHashAndPassword itself
+	// accepts any of them regardless of which one GenerateFromPassword
+	// produces.
+	bcryptPrefix = "$2"
+)
+
+// bcryptHasher implements Hasher using golang.org/x/crypto/bcrypt.
+type bcryptHasher struct {
+	mu         sync.RWMutex
+	quickCost  int
+	strongCost int
+}
+
+// newBcryptHasher returns a bcryptHasher with no cost tuned yet.
+func newBcryptHasher() *bcryptHasher {
+	return &bcryptHasher{}
+}
+
+// GenerateQuick returns a hash produced using the tuned quick-tier cost.
+func (h *bcryptHasher) GenerateQuick(pass string) (string, error) {
+	h.mu.RLock()
+	c := h.quickCost
+	h.mu.RUnlock()
+
+	b, err := bcrypt.GenerateFromPassword([]byte(pass), c)
+	return string(b), err
+}
+
+// GenerateStrong returns a hash produced using the tuned strong-tier cost.
+func (h *bcryptHasher) GenerateStrong(pass string) (string, error) {
+	h.mu.RLock()
+	c := h.strongCost
+	h.mu.RUnlock()
+
+	b, err := bcrypt.GenerateFromPassword([]byte(pass), c)
+	return string(b), err
+}
+
+// Compare returns an error if hash and pass do not match.
+func (h *bcryptHasher) Compare(hash, pass string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass))
+}
+
+// Cost returns the bcrypt cost encoded in hash.
+func (h *bcryptHasher) Cost(hash string) (int, error) {
+	return bcrypt.Cost([]byte(hash))
+}
+
+// QuickCost returns the cost set by the most recent Tune.
+func (h *bcryptHasher) QuickCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.quickCost
+}
+
+// StrongCost returns the cost set by the most recent Tune.
+func (h *bcryptHasher) StrongCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.strongCost
+}
+
+// TuneParams returns the tuned cost as a single-element slice for each
+// tier.
+func (h *bcryptHasher) TuneParams() (quick, strong []int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return []int{h.quickCost}, []int{h.strongCost}
+}
+
+// SetTuneParams restores quickCost and strongCost from quick[0] and
+// strong[0].
+func (h *bcryptHasher) SetTuneParams(quick, strong []int) {
+	h.mu.Lock()
+	h.quickCost = quick[0]
+	h.strongCost = strong[0]
+	h.mu.Unlock()
+}
+
+// Prefix returns the prefix matching any bcrypt hash, regardless of minor
+// version.
+func (h *bcryptHasher) Prefix() string {
+	return bcryptPrefix
+}
+
+// Algorithm returns AlgorithmBcrypt.
+func (h *bcryptHasher) Algorithm() Algorithm {
+	return AlgorithmBcrypt
+}
+
+// Tune sets quickCost and strongCost based on the provided max times.
+// Appropriate costs are determined by producing a handful of low-cost
+// hashes, then using the resulting durations to interpolate the durations
+// of hashes with higher costs.
+func (h *bcryptHasher) Tune(quickMax, strongMax time.Duration) error {
+	var qc, sc int
+
+	cts := []time.Duration{0}
+	for i := 1; i <= maxCost; i++ {
+		if i < minCost {
+			cts = append(cts, 0)
+			continue
+		}
+
+		if cts[i-1] < interpTime {
+			t1 := time.Now()
+			_, err := bcrypt.GenerateFromPassword([]byte(testStr), i)
+			d := time.Since(t1)
+			if err != nil {
+				return err
+			}
+
+			cts = append(cts, d)
+			continue
+		}
+
+		tct := cts[i-1] * 2
+		tct = tct - (tct % (time.Millisecond * 10))
+		cts = append(cts, tct)
+	}
+
+	for k := range cts {
+		if qc == 0 && len(cts) > k+1 && cts[k+1] > quickMax {
+			qc = k
+		}
+		if sc == 0 && len(cts) > k+1 && cts[k+1] > strongMax {
+			sc = k
+		}
+	}
+
+	if qc == 0 || sc == 0 {
+		return ErrTuneTooFast
+	}
+
+	h.mu.Lock()
+	h.quickCost = qc
+	h.strongCost = sc
+	h.mu.Unlock()
+
+	return nil
+}
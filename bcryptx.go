@@ -1,17 +1,23 @@
-// Package bcryptx automates the tuning of bcrypt costs based on an
-// environment's available processing resources.  Concurrency throttling is
-// provided, as well as convenience functions for making use of tuned costs
-// with bcrypt functions.
+// Package bcryptx automates the tuning of password-hashing costs based on
+// an environment's available processing resources. Concurrency throttling
+// is provided, as well as convenience functions for making use of tuned
+// costs with bcrypt, scrypt, and argon2id.
 //
 // quickCost should be used when a hash should be accessible quickly.
 // strongCost should be used when the delay of processing can be mitigated.
+//
+// CompareHashAndPass auto-detects which of the three algorithms produced a
+// given hash, so a database of hashes produced by more than one of them
+// (e.g. while migrating from bcrypt to argon2id) keeps verifying correctly
+// regardless of which Algorithm a Bcrypter is currently configured to
+// generate with.
 package bcryptx
 
 import (
+	"context"
+	"log"
 	"sync"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -26,33 +32,92 @@ const (
 	// GenConcurrency is the default goroutine count used for Gen*FromPass.
 	GenConcurrency = 2
 
-	minCost    = bcrypt.MinCost
-	maxCost    = bcrypt.MaxCost
-	interpTime = time.Millisecond * 50
-	testStr    = "#!PnutBudr"
+	// HashTimeoutFactor sets the hard runtime cap used by the
+	// Gen*FromPassContext methods, as a multiple of the tier's tuned max
+	// time.
+	HashTimeoutFactor = 4
+
+	// RetuneWindow is the default number of trailing Gen*FromPass
+	// durations considered by Options.AdaptiveRetune.
+	RetuneWindow = 20
+
+	// RetuneTolerance is the default fraction of drift outside
+	// [max/2, max] tolerated by Options.AdaptiveRetune before a re-tune
+	// is triggered.
+	RetuneTolerance = 0.25
 )
 
 // Options holds values to be passed to New.
 type Options struct {
+	// Algorithm selects which Hasher backs GenQuickFromPass,
+	// GenStrongFromPass, and Tune. It does not affect which hashes
+	// CompareHashAndPass can verify. Defaults to AlgorithmBcrypt.
+	Algorithm Algorithm
+
 	// GenQuickMaxTime is the max time used for tuning Bcrypter.quickCost.
-	GenQuickMaxTime  time.Duration
+	GenQuickMaxTime time.Duration
 
 	// GenStrongMaxTime is the max time used for tuning Bcrypter.strongCost.
 	GenStrongMaxTime time.Duration
 
 	// GenConcurrency is the goroutine count used for Gen*FromPass.
-	GenConcurrency   int
+	GenConcurrency int
+
+	// MaxMemoryMiB is the memory ceiling observed while tuning Algorithm
+	// values of AlgorithmScrypt or AlgorithmArgon2id. Defaults to
+	// DefaultMaxMemoryMiB; unused by AlgorithmBcrypt.
+	MaxMemoryMiB int
+
+	// MaxAcceptedCost is the highest cost ValidateHash and NeedsRehash
+	// will accept. A hash above this cost was tuned on hardware far more
+	// capable than this host's, and verifying it here risks exceeding a
+	// caller's request timeout. Zero disables the ceiling.
+	MaxAcceptedCost int
+
+	// TuneCachePath, if set, persists tuned costs to a JSON file at this
+	// path, keyed by a fingerprint of the host and tuning configuration,
+	// so New followed by Tune can skip recalibrating on a host it has
+	// already tuned for. Ignored if TuneCache is set.
+	TuneCachePath string
+
+	// TuneCache, if set, overrides TuneCachePath with a custom TuneCache
+	// implementation.
+	TuneCache TuneCache
+
+	// AdaptiveRetune enables background re-tuning: GenQuickFromPass and
+	// GenStrongFromPass record their observed durations, and once a full
+	// RetuneWindow of samples has been collected for a tier, a trailing
+	// mean that drifts outside [max/2, max] by more than RetuneTolerance
+	// triggers a re-tune (via ForceRetune) in the background.
+	AdaptiveRetune bool
+
+	// RetuneWindow is the number of trailing samples considered for
+	// AdaptiveRetune. Defaults to RetuneWindow if zero.
+	RetuneWindow int
+
+	// RetuneTolerance is the fraction of drift outside [max/2, max]
+	// tolerated before AdaptiveRetune triggers a re-tune. Defaults to
+	// RetuneTolerance if zero.
+	RetuneTolerance float64
 }
 
-// Bcrypter provides an API for bcrypt functions with "quick" or "strong" costs.
-// Tune is called on first use of Gen*FromPass if not already called directly.
+// Bcrypter provides an API for password-hash functions with "quick" or
+// "strong" costs. Tune is called on first use of Gen*FromPass if not
+// already called directly.
 type Bcrypter struct {
-	mu         *sync.RWMutex
-	tuningWg   *sync.WaitGroup
-	options    *Options
-	quickCost  int
-	strongCost int
-	concCount  chan bool
+	mu          *sync.RWMutex
+	tuneMu      *sync.Mutex
+	options     *Options
+	hasher      Hasher
+	tuned       bool
+	concCount   chan bool
+	cache       TuneCache
+	cacheKey    string
+	quickStats  *tierStats
+	strongStats *tierStats
+	retuning    bool
+	altMu       *sync.Mutex
+	altHashers  map[Algorithm]Hasher
 }
 
 // New returns a new Bcrypter based on Options values or defaults.
@@ -71,144 +136,446 @@ func New(opts *Options) *Bcrypter {
 	if opts.GenConcurrency == 0 {
 		opts.GenConcurrency = GenConcurrency
 	}
+	if opts.MaxMemoryMiB == 0 {
+		opts.MaxMemoryMiB = DefaultMaxMemoryMiB
+	}
+	if opts.RetuneWindow == 0 {
+		opts.RetuneWindow = RetuneWindow
+	}
+	if opts.RetuneTolerance == 0 {
+		opts.RetuneTolerance = RetuneTolerance
+	}
+
+	bc := &Bcrypter{
+		options: opts, mu: &sync.RWMutex{}, tuneMu: &sync.Mutex{}, altMu: &sync.Mutex{},
+		hasher:      newHasher(opts.Algorithm, opts),
+		concCount:   make(chan bool, opts.GenConcurrency),
+		quickStats:  newTierStats(opts.RetuneWindow),
+		strongStats: newTierStats(opts.RetuneWindow),
+		altHashers:  map[Algorithm]Hasher{},
+	}
 
-	return &Bcrypter{
-		options: opts, mu: &sync.RWMutex{}, tuningWg: &sync.WaitGroup{},
-		concCount: make(chan bool, opts.GenConcurrency),
+	if opts.TuneCache != nil {
+		bc.cache = opts.TuneCache
+	} else if opts.TuneCachePath != "" {
+		bc.cache = newFileTuneCache(opts.TuneCachePath)
+	}
+	if bc.cache != nil {
+		bc.cacheKey = fingerprint(opts)
 	}
+
+	return bc
 }
 
 // GenQuickFromPass returns a hash produced using Bcrypter.quickCost or any
 // error encountered during handling.
 func (bc *Bcrypter) GenQuickFromPass(pass string) (string, error) {
+	if err := bc.ensureTuned(); err != nil {
+		return "", err
+	}
+
 	bc.concCount <- true
 	defer func() { <-bc.concCount }()
-	b, err := bcrypt.GenerateFromPassword([]byte(pass), bc.CurrentQuickCost())
-	return string(b), err
+
+	t1 := time.Now()
+	hash, err := bc.hasher.GenerateQuick(pass)
+	bc.recordSample(bc.quickStats, time.Since(t1), bc.options.GenQuickMaxTime)
+	return hash, err
 }
 
 // GenStrongFromPass returns a hash produced using Bcrypter.strongCost or any
 // error encountered during handling.
 func (bc *Bcrypter) GenStrongFromPass(pass string) (string, error) {
+	if err := bc.ensureTuned(); err != nil {
+		return "", err
+	}
+
 	bc.concCount <- true
 	defer func() { <-bc.concCount }()
-	b, err := bcrypt.GenerateFromPassword([]byte(pass), bc.CurrentStrongCost())
-	return string(b), err
+
+	t1 := time.Now()
+	hash, err := bc.hasher.GenerateStrong(pass)
+	bc.recordSample(bc.strongStats, time.Since(t1), bc.options.GenStrongMaxTime)
+	return hash, err
+}
+
+// GenQuickFromPassContext is the GenQuickFromPass variant that respects
+// ctx.Done() and enforces a hard runtime cap of HashTimeoutFactor times the
+// tuned quick max time, returning ErrHashTimeout if it is exceeded.
+// bcrypt.GenerateFromPassword cannot itself be cancelled, so the hash keeps
+// running in the background after a cancelled or timed-out return; the
+// concurrency slot it holds is released when it finishes, not when this
+// method returns.
+func (bc *Bcrypter) GenQuickFromPassContext(ctx context.Context, pass string) (string, error) {
+	return bc.genFromPassContext(ctx, bc.options.GenQuickMaxTime, bc.hasher.GenerateQuick, pass)
+}
+
+// GenStrongFromPassContext is the GenStrongFromPass variant that respects
+// ctx.Done() and enforces a hard runtime cap of HashTimeoutFactor times the
+// tuned strong max time, returning ErrHashTimeout if it is exceeded.
+// bcrypt.GenerateFromPassword cannot itself be cancelled, so the hash keeps
+// running in the background after a cancelled or timed-out return; the
+// concurrency slot it holds is released when it finishes, not when this
+// method returns.
+func (bc *Bcrypter) GenStrongFromPassContext(ctx context.Context, pass string) (string, error) {
+	return bc.genFromPassContext(ctx, bc.options.GenStrongMaxTime, bc.hasher.GenerateStrong, pass)
+}
+
+// genFromPassContext runs ensureTuned and gen in a goroutine that reserves
+// and releases bc.concCount for gen's lifetime only, so a caller that gives
+// up early (via ctx.Done() or the hard runtime cap) doesn't hold the slot
+// open. Tuning runs in that same goroutine, not before it's spawned, so an
+// already-cancelled or soon-to-expire ctx is honored even on an untuned
+// Bcrypter's first call instead of blocking for the full calibration. The
+// hard runtime cap starts once tuning has finished, so calibration time
+// never counts against it.
+func (bc *Bcrypter) genFromPassContext(
+	ctx context.Context, maxTime time.Duration, gen func(string) (string, error), pass string,
+) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	type result struct {
+		hash string
+		err  error
+	}
+
+	tuned := make(chan struct{})
+	done := make(chan result, 1)
+	go func() {
+		if err := bc.ensureTuned(); err != nil {
+			done <- result{"", err}
+			return
+		}
+		close(tuned)
+
+		bc.concCount <- true
+		defer func() { <-bc.concCount }()
+
+		h, err := gen(pass)
+		done <- result{h, err}
+	}()
+
+	select {
+	case <-tuned:
+	case r := <-done:
+		return r.hash, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	hardCap := time.NewTimer(maxTime * HashTimeoutFactor)
+	defer hardCap.Stop()
+
+	select {
+	case r := <-done:
+		return r.hash, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-hardCap.C:
+		bc.markForRetune()
+		return "", ErrHashTimeout
+	}
+}
+
+// markForRetune logs a warning and clears the tuned flag so the next call
+// to Tune (direct, or via the first subsequent Gen*FromPass) recalibrates
+// costs, on the theory that a hard-cap trip means the tuned costs no
+// longer fit this host.
+func (bc *Bcrypter) markForRetune() {
+	bc.mu.Lock()
+	bc.tuned = false
+	bc.mu.Unlock()
+
+	if bc.cache != nil {
+		bc.cache.Delete(bc.cacheKey)
+	}
+
+	log.Printf("bcryptx: hash generation exceeded its hard runtime cap; marking Bcrypter for re-tune")
+}
+
+// recordSample records d into stats and, if Options.AdaptiveRetune is
+// enabled and stats now holds a full RetuneWindow, triggers a background
+// re-tune when the trailing mean has drifted outside [max/2, max] by more
+// than RetuneTolerance.
+func (bc *Bcrypter) recordSample(stats *tierStats, d, max time.Duration) {
+	if !bc.options.AdaptiveRetune {
+		return
+	}
+
+	stats.record(d)
+	if !stats.full() {
+		return
+	}
+
+	mean, _, _ := stats.meanP95()
+	tol := bc.options.RetuneTolerance
+	lower := (max / 2) - time.Duration(float64(max/2)*tol)
+	upper := max + time.Duration(float64(max)*tol)
+	if mean >= lower && mean <= upper {
+		return
+	}
+
+	bc.mu.Lock()
+	if bc.retuning {
+		bc.mu.Unlock()
+		return
+	}
+	bc.retuning = true
+	bc.mu.Unlock()
+
+	go func() {
+		defer func() {
+			bc.mu.Lock()
+			bc.retuning = false
+			bc.mu.Unlock()
+			stats.reset()
+		}()
+
+		if err := bc.ForceRetune(); err != nil {
+			log.Printf("bcryptx: adaptive re-tune failed: %v", err)
+		}
+	}()
+}
+
+// Stats reports the current cost, sample count, and mean/p95 observed
+// duration for each tier. Samples, Mean, and P95 are zero unless
+// Options.AdaptiveRetune is enabled.
+func (bc *Bcrypter) Stats() (quick, strong TierStats) {
+	qMean, qP95, qN := bc.quickStats.meanP95()
+	sMean, sP95, sN := bc.strongStats.meanP95()
+
+	quick = TierStats{Cost: bc.CurrentQuickCost(), Samples: qN, Mean: qMean, P95: qP95}
+	strong = TierStats{Cost: bc.CurrentStrongCost(), Samples: sN, Mean: sMean, P95: sP95}
+	return quick, strong
 }
 
 // CompareHashAndPass returns an error if comparison fails or any error
-// encountered during handling.
+// encountered during handling. The algorithm used for comparison is
+// detected from hash's encoded prefix, independent of Bcrypter's
+// configured Algorithm.
 func (bc *Bcrypter) CompareHashAndPass(hash, pass string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass))
+	h, err := hasherForHash(hash)
+	if err != nil {
+		return err
+	}
+	return h.Compare(hash, pass)
+}
+
+// ValidateHash returns ErrMalformed if hash is not a well-formed,
+// recognized hash, ErrCostTooLow if its cost is below the quickCost tuned
+// for the algorithm that produced it, or ErrCostTooHigh if its cost is
+// above Options.MaxAcceptedCost. MaxAcceptedCost is only enforced against
+// hashes produced by Bcrypter's configured Algorithm, since cost scales
+// are not comparable across algorithms (bcrypt's cost is 4-31; argon2id's
+// is log2(memKiB)*10+time) and Options has no per-algorithm ceiling.
+func (bc *Bcrypter) ValidateHash(hash string) error {
+	h, err := hasherForHash(hash)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	c, err := h.Cost(hash)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	quickCost, sameAlgorithm := bc.quickCostFor(h.Algorithm())
+	if c < quickCost {
+		return ErrCostTooLow
+	}
+	if sameAlgorithm && bc.options.MaxAcceptedCost > 0 && c > bc.options.MaxAcceptedCost {
+		return ErrCostTooHigh
+	}
+	return nil
+}
+
+// quickCostFor returns the tuned quick-tier cost for alg and whether alg is
+// Bcrypter's configured Algorithm. For any other algorithm, it lazily tunes
+// and caches a dedicated Hasher so ValidateHash can compare a hash's cost
+// against the range tuned for the algorithm that actually produced it,
+// rather than whichever algorithm Bcrypter currently generates with. That
+// tuning goes through bc.cache, keyed per-algorithm, the same way the
+// primary Hasher's does in tune, so a mixed-algorithm login flow doesn't
+// pay a fresh calibration on every new Bcrypter instance.
+func (bc *Bcrypter) quickCostFor(alg Algorithm) (cost int, sameAlgorithm bool) {
+	if alg == bc.options.Algorithm {
+		return bc.CurrentQuickCost(), true
+	}
+
+	bc.altMu.Lock()
+	defer bc.altMu.Unlock()
+
+	h, ok := bc.altHashers[alg]
+	if !ok {
+		h = newHasher(alg, bc.options)
+		bc.tuneAlt(h, alg)
+		bc.altHashers[alg] = h
+	}
+	return h.QuickCost(), false
+}
+
+// tuneAlt tunes h, the lazily-created Hasher for alg (an algorithm other
+// than bc.options.Algorithm), loading its tier parameters from bc.cache
+// under a per-algorithm key if a matching entry exists, and storing the
+// result back otherwise. Callers must hold bc.altMu.
+func (bc *Bcrypter) tuneAlt(h Hasher, alg Algorithm) {
+	var key string
+	if bc.cache != nil {
+		key = altFingerprint(bc.options, alg)
+		if entry, ok := bc.cache.Load(key); ok {
+			h.SetTuneParams(entry.Quick, entry.Strong)
+			return
+		}
+	}
+
+	if err := h.Tune(bc.options.GenQuickMaxTime, bc.options.GenStrongMaxTime); err != nil {
+		log.Printf("bcryptx: tuning %v for hash validation failed: %v", alg, err)
+		return
+	}
+
+	if bc.cache != nil {
+		quick, strong := h.TuneParams()
+		bc.cache.Store(key, TuneCacheEntry{Quick: quick, Strong: strong})
+	}
 }
 
-// Tune sets the quick and strong costs based on provided max times.
-// Appropriate costs are determined by producing a handful of low-cost hashes,
-// then using the resulting durations to interpolate the durations of hashes
-// with higher costs.
-func (bc *Bcrypter) Tune() {
-	bc.tuningWg.Wait()
-	bc.tuningWg.Add(1)
-	bc.tune(bc.tuningWg)
+// NeedsRehash reports whether hash should be regenerated from its
+// just-verified plaintext: its cost has drifted out of the range this host
+// currently considers acceptable, either below quickCost (this host is
+// faster than whatever tuned it) or above MaxAcceptedCost (it is slower).
+// The typical use is a login flow that, on successful CompareHashAndPass,
+// calls NeedsRehash and, if true, regenerates and stores a new hash.
+func (bc *Bcrypter) NeedsRehash(hash string) bool {
+	switch bc.ValidateHash(hash) {
+	case ErrCostTooLow, ErrCostTooHigh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tune sets the quick and strong costs based on configured max times.
+// tuneMu serializes tuning across concurrent callers (e.g. ensureTuned from
+// a live Gen*FromPass and a background AdaptiveRetune), so two tunes never
+// run at once and readers never observe a half-tuned Bcrypter.
+func (bc *Bcrypter) Tune() error {
+	bc.tuneMu.Lock()
+	defer bc.tuneMu.Unlock()
+
+	return bc.tune()
 }
 
 // IsCostQuick returns false if the apparent cost of the hash is lower than
-// the provided cost, or if any errors are encountered during hash analysis.
+// the current quickCost, or if any errors are encountered during hash
+// analysis.
 func (bc *Bcrypter) IsCostQuick(hash string) bool {
-	return testHash(hash, bc.CurrentQuickCost())
+	return bc.testCost(hash, bc.CurrentQuickCost())
 }
 
 // IsCostStrong returns false if the apparent cost of the hash is lower than
-// the provided cost, or if any errors are encountered during hash analysis.
+// the current strongCost, or if any errors are encountered during hash
+// analysis.
 func (bc *Bcrypter) IsCostStrong(hash string) bool {
-	return testHash(hash, bc.CurrentStrongCost())
+	return bc.testCost(hash, bc.CurrentStrongCost())
 }
 
-// CurrentQuickCost returns the quickCost as set by Tune.
+// CurrentQuickCost returns the quickCost as set by Tune. If tuning has not
+// run yet and an attempt to tune now fails, the failure is logged and
+// whatever quickCost the Hasher defaults to is returned.
 func (bc *Bcrypter) CurrentQuickCost() int {
-	bc.tuningWg.Wait()
-	bc.mu.RLock()
-	c := bc.quickCost
-	bc.mu.RUnlock()
-
-	if c == 0 {
-		bc.Tune()
-		bc.mu.RLock()
-		c = bc.quickCost
-		bc.mu.RUnlock()
+	if err := bc.ensureTuned(); err != nil {
+		log.Printf("bcryptx: tuning failed: %v", err)
 	}
-	return c
+	return bc.hasher.QuickCost()
 }
 
-// CurrentStrongCost returns the strongCost as set by Tune.
+// CurrentStrongCost returns the strongCost as set by Tune. If tuning has not
+// run yet and an attempt to tune now fails, the failure is logged and
+// whatever strongCost the Hasher defaults to is returned.
 func (bc *Bcrypter) CurrentStrongCost() int {
-	bc.tuningWg.Wait()
-	bc.mu.RLock()
-	c := bc.strongCost
-	bc.mu.RUnlock()
-
-	if c == 0 {
-		bc.Tune()
-		bc.mu.RLock()
-		c = bc.strongCost
-		bc.mu.RUnlock()
+	if err := bc.ensureTuned(); err != nil {
+		log.Printf("bcryptx: tuning failed: %v", err)
 	}
-	return c
+	return bc.hasher.StrongCost()
 }
 
-// tune sets Bcrypter.quickCost and Bcrypter.strongCost, and panics on any
-// error or if any cost is unable to be determined.
-func (bc *Bcrypter) tune(wg *sync.WaitGroup) {
-	defer wg.Done()
-	var qc, sc int
+func (bc *Bcrypter) isTuned() bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.tuned
+}
 
-	cts := []time.Duration{0}
-	for i := 1; i <= maxCost; i++ {
-		if i < minCost {
-			cts = append(cts, 0)
-			continue
-		}
+// ensureTuned tunes bc if it is not already tuned, and otherwise blocks
+// until any in-flight tune (direct or via a concurrent ensureTuned) has
+// completed. isTuned is rechecked under tuneMu so a caller that lost the
+// race to acquire it doesn't redundantly retune.
+func (bc *Bcrypter) ensureTuned() error {
+	if bc.isTuned() {
+		return nil
+	}
 
-		if cts[i-1] < interpTime {
-			t1 := time.Now()
-			_, err := bcrypt.GenerateFromPassword([]byte(testStr), i)
-			d := time.Since(t1)
-			if err != nil {
-				panic("Failed to tune bcryptx: " + err.Error())
-			}
+	bc.tuneMu.Lock()
+	defer bc.tuneMu.Unlock()
 
-			cts = append(cts, d)
-			continue
-		}
+	if bc.isTuned() {
+		return nil
+	}
+	return bc.tune()
+}
 
-		tct := cts[i-1] * 2
-		tct = tct - (tct % (time.Millisecond * 10))
-		cts = append(cts, tct)
+// ForceRetune invalidates any cached tune entry for this Bcrypter and
+// recalibrates costs immediately.
+func (bc *Bcrypter) ForceRetune() error {
+	if bc.cache != nil {
+		bc.cache.Delete(bc.cacheKey)
 	}
 
-	for k := range cts {
-		if qc == 0 && len(cts) > k+1 && cts[k+1] > bc.options.GenQuickMaxTime {
-			qc = k
-		}
-		if sc == 0 && len(cts) > k+1 && cts[k+1] > bc.options.GenStrongMaxTime {
-			sc = k
+	bc.mu.Lock()
+	bc.tuned = false
+	bc.mu.Unlock()
+
+	bc.quickStats.reset()
+	bc.strongStats.reset()
+
+	return bc.Tune()
+}
+
+// tune loads quickCost/strongCost from bc.cache if a matching entry
+// exists, and otherwise delegates to the configured Hasher's Tune and
+// stores the result back to bc.cache. Callers must hold bc.tuneMu.
+func (bc *Bcrypter) tune() error {
+	if bc.cache != nil {
+		if entry, ok := bc.cache.Load(bc.cacheKey); ok {
+			bc.hasher.SetTuneParams(entry.Quick, entry.Strong)
+			bc.mu.Lock()
+			bc.tuned = true
+			bc.mu.Unlock()
+			return nil
 		}
 	}
 
-	if qc == 0 || sc == 0 {
-		panic("bcrypt hash times are too low.")
+	if err := bc.hasher.Tune(bc.options.GenQuickMaxTime, bc.options.GenStrongMaxTime); err != nil {
+		return err
 	}
 
 	bc.mu.Lock()
-	bc.quickCost = qc
-	bc.strongCost = sc
+	bc.tuned = true
 	bc.mu.Unlock()
+
+	if bc.cache != nil {
+		quick, strong := bc.hasher.TuneParams()
+		bc.cache.Store(bc.cacheKey, TuneCacheEntry{Quick: quick, Strong: strong})
+	}
+
+	return nil
 }
 
-// test returns false if the apparent cost of the hash is lower than the
-// provided cost, or if any errors are encountered during hash analysis.
-func testHash(hash string, cost int) bool {
-	c, err := bcrypt.Cost([]byte(hash))
+// testCost returns false if the apparent cost of hash is lower than cost,
+// or if any errors are encountered during hash analysis.
+func (bc *Bcrypter) testCost(hash string, cost int) bool {
+	c, err := bc.hasher.Cost(hash)
 	if err != nil || c < cost {
 		return false
 	}
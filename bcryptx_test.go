@@ -1,8 +1,13 @@
 package bcryptx_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -164,3 +169,379 @@ func TestCustomTimeSetup(t *testing.T) {
 		}
 	}
 }
+
+func TestAlgorithms(t *testing.T) {
+	algs := []bcryptx.Algorithm{
+		bcryptx.AlgorithmBcrypt,
+		bcryptx.AlgorithmScrypt,
+		bcryptx.AlgorithmArgon2id,
+	}
+
+	for _, alg := range algs {
+		bcx := bcryptx.New(&bcryptx.Options{
+			Algorithm:        alg,
+			GenQuickMaxTime:  time.Millisecond * 50,
+			GenStrongMaxTime: time.Millisecond * 100,
+			MaxMemoryMiB:     8,
+		})
+
+		hash, err := bcx.GenQuickFromPass(testPass)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bcx.CompareHashAndPass(hash, testPass); err != nil {
+			t.Fatal(err)
+		}
+		if err := bcx.CompareHashAndPass(hash, "wrong"); err == nil {
+			t.Fatal(errNoErr)
+		}
+		if err := bcx.ValidateHash(hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArgon2idMaxMemoryMiBBelowFloor(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmArgon2id,
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     1,
+	})
+
+	hash, err := bcx.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m int
+	if _, err := fmt.Sscanf(hash, "$argon2id$m=%d,", &m); err != nil {
+		t.Fatalf("could not parse m= from hash %q: %v", hash, err)
+	}
+	if want := 1 * 1024; m > want {
+		t.Fatalf("m = %d, want <= %d (MaxMemoryMiB ceiling)", m, want)
+	}
+}
+
+func TestArgon2idCompareMismatchNotMalformed(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmArgon2id,
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     8,
+	})
+
+	hash, err := bcx.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bcx.CompareHashAndPass(hash, "wrong"); err == nil {
+		t.Fatal(errNoErr)
+	} else if errors.Is(err, bcryptx.ErrMalformed) {
+		t.Fatalf("got %v, want a mismatch error distinct from ErrMalformed", err)
+	}
+}
+
+func TestCompareHashAndPassBcryptMinorVersions(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+	})
+
+	hash, err := bcx.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, minor := range []byte{'a', 'b', 'x', 'y'} {
+		h := "$2" + string(minor) + hash[3:]
+		if err := bcx.CompareHashAndPass(h, testPass); err != nil {
+			t.Errorf("CompareHashAndPass(%q, ...) = %v, want nil", h, err)
+		}
+	}
+}
+
+func TestCompareHashAndPassMixedAlgorithms(t *testing.T) {
+	bcrypt := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+	})
+	scrypt := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmScrypt,
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     8,
+	})
+	argon2id := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmArgon2id,
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     8,
+	})
+
+	h1, err := bcrypt.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := scrypt.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3, err := argon2id.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Any Bcrypter can verify any of the three, regardless of the
+	// Algorithm it was configured with.
+	for _, h := range []string{h1, h2, h3} {
+		if err := bcrypt.CompareHashAndPass(h, testPass); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPass(testPass, testPass); err != bcryptx.ErrUnknownAlgorithm {
+		t.Fatalf("got %v, want %v", err, bcryptx.ErrUnknownAlgorithm)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	strong := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime:  time.Millisecond * 800,
+		GenStrongMaxTime: time.Millisecond * 1600,
+	})
+	quick := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+		MaxAcceptedCost: strong.CurrentQuickCost() - 1,
+	})
+
+	h, err := strong.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := quick.ValidateHash(h); err != bcryptx.ErrCostTooHigh {
+		t.Fatalf("got %v, want %v", err, bcryptx.ErrCostTooHigh)
+	}
+	if !quick.NeedsRehash(h) {
+		t.Fatal("expected a too-high-cost hash to need rehashing")
+	}
+
+	h, err = quick.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := quick.ValidateHash(h); err != nil {
+		t.Fatal(err)
+	}
+	if quick.NeedsRehash(h) {
+		t.Fatal("did not expect a freshly tuned hash to need rehashing")
+	}
+}
+
+func TestNeedsRehashMixedAlgorithms(t *testing.T) {
+	bcrypt := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+	})
+	argon2id := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmArgon2id,
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     8,
+	})
+
+	h, err := bcrypt.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// argon2id's cost scale (log2(memKiB)*10+time) is numerically larger
+	// than bcrypt's (4-31), so a fresh bcrypt hash must not be judged
+	// against argon2id's quickCost.
+	if err := argon2id.ValidateHash(h); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+	if argon2id.NeedsRehash(h) {
+		t.Fatal("did not expect a freshly tuned bcrypt hash to need rehashing under an argon2id-configured Bcrypter")
+	}
+}
+
+func TestGenQuickFromPassContext(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+	})
+
+	hash, err := bcx.GenQuickFromPassContext(context.Background(), testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcx.CompareHashAndPass(hash, testPass); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := bcx.GenQuickFromPassContext(ctx, testPass); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGenQuickFromPassContextCancelledBeforeTune(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime: time.Millisecond * 50,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t1 := time.Now()
+	if _, err := bcx.GenQuickFromPassContext(ctx, testPass); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if got := time.Since(t1); got > time.Millisecond*50 {
+		t.Fatalf("GenQuickFromPassContext took %v on an untuned Bcrypter with a pre-cancelled ctx, want it to return promptly", got)
+	}
+}
+
+func TestTuneCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tune.json")
+	opts := &bcryptx.Options{
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		TuneCachePath:    path,
+	}
+
+	bcx := bcryptx.New(opts)
+	if err := bcx.Tune(); err != nil {
+		t.Fatal(err)
+	}
+	wantQuick := bcx.CurrentQuickCost()
+	wantStrong := bcx.CurrentStrongCost()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected %s to exist: %v", path, err)
+	}
+
+	// A second Bcrypter with the same fingerprint should load the cached
+	// costs instead of recalibrating.
+	bcx2 := bcryptx.New(opts)
+	if err := bcx2.Tune(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bcx2.CurrentQuickCost(); got != wantQuick {
+		t.Errorf("CurrentQuickCost() = %d, want %d", got, wantQuick)
+	}
+	if got := bcx2.CurrentStrongCost(); got != wantStrong {
+		t.Errorf("CurrentStrongCost() = %d, want %d", got, wantStrong)
+	}
+
+	if err := bcx2.ForceRetune(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTuneCacheAltAlgorithm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tune.json")
+	opts := &bcryptx.Options{
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		MaxMemoryMiB:     8,
+		TuneCachePath:    path,
+	}
+
+	argon2id := bcryptx.New(&bcryptx.Options{
+		Algorithm:        bcryptx.AlgorithmArgon2id,
+		GenQuickMaxTime:  opts.GenQuickMaxTime,
+		GenStrongMaxTime: opts.GenStrongMaxTime,
+		MaxMemoryMiB:     opts.MaxMemoryMiB,
+	})
+	h, err := argon2id.GenQuickFromPass(testPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A bcrypt-configured Bcrypter validating an argon2id hash lazily
+	// tunes an alt Hasher for it; that tuning should land in the shared
+	// TuneCache under a distinct key rather than bypassing it.
+	bcx := bcryptx.New(opts)
+	if err := bcx.ValidateHash(h); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected %s to exist: %v", path, err)
+	}
+	if !strings.Contains(string(entries), "Quick") {
+		t.Fatalf("expected tune cache at %s to contain an entry, got %q", path, entries)
+	}
+
+	// A second Bcrypter sharing the same cache must not pay a fresh
+	// calibration to validate the same alt-algorithm hash.
+	bcx2 := bcryptx.New(opts)
+	if err := bcx2.ValidateHash(h); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatsAndAdaptiveRetune(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+		AdaptiveRetune:   true,
+		RetuneWindow:     3,
+	})
+
+	initialCost := bcx.CurrentQuickCost()
+	for i := 0; i < 3; i++ {
+		if _, err := bcx.GenQuickFromPass(testPass); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	quick, strong := bcx.Stats()
+	if quick.Samples != 3 {
+		t.Errorf("quick.Samples = %d, want 3", quick.Samples)
+	}
+	if quick.Mean <= 0 || quick.P95 <= 0 {
+		t.Errorf("quick.Mean = %v, quick.P95 = %v, want both > 0", quick.Mean, quick.P95)
+	}
+	if strong.Samples != 0 {
+		t.Errorf("strong.Samples = %d, want 0", strong.Samples)
+	}
+
+	// A within-range quick tier shouldn't have triggered a re-tune.
+	if got := bcx.CurrentQuickCost(); got != initialCost {
+		t.Errorf("CurrentQuickCost() = %d, want unchanged %d", got, initialCost)
+	}
+}
+
+func TestConcurrentForceRetuneAndGen(t *testing.T) {
+	bcx := bcryptx.New(&bcryptx.Options{
+		GenQuickMaxTime:  time.Millisecond * 50,
+		GenStrongMaxTime: time.Millisecond * 100,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		if err := bcx.ForceRetune(); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if _, err := bcx.GenQuickFromPass(testPass); err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := bcx.ForceRetune(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,28 @@
+package bcryptx
+
+import "errors"
+
+// ErrTuneTooFast is returned by Tune when the host is fast enough that no
+// supported cost takes longer than one of the max times to hash, making it
+// impossible to pick a quick and a strong cost.
+var ErrTuneTooFast = errors.New("bcryptx: hash times are too low to tune")
+
+// ErrMalformed is returned by ValidateHash when a hash cannot be decoded,
+// including when its prefix does not match any known algorithm.
+var ErrMalformed = errors.New("bcryptx: malformed hash")
+
+// ErrCostTooLow is returned by ValidateHash when a hash's cost is below the
+// current quickCost, meaning it was tuned on slower hardware than this
+// host's and should be rehashed.
+var ErrCostTooLow = errors.New("bcryptx: hash cost is too low")
+
+// ErrCostTooHigh is returned by ValidateHash when a hash's cost is above
+// Options.MaxAcceptedCost, meaning verifying it on this host risks
+// exceeding a caller's request timeout.
+var ErrCostTooHigh = errors.New("bcryptx: hash cost is too high")
+
+// ErrHashTimeout is returned by the Gen*FromPassContext methods when
+// generation is still running after HashTimeoutFactor times its tuned max
+// time. The underlying hash keeps running in the background; only the
+// caller's wait is abandoned.
+var ErrHashTimeout = errors.New("bcryptx: hash generation exceeded its hard runtime cap")
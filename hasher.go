@@ -0,0 +1,110 @@
+package bcryptx
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Algorithm identifies a password-hashing algorithm supported by New.
+type Algorithm int
+
+// Supported Algorithm values.
+const (
+	// AlgorithmBcrypt selects bcrypt (the default).
+	AlgorithmBcrypt Algorithm = iota
+
+	// AlgorithmScrypt selects scrypt.
+	AlgorithmScrypt
+
+	// AlgorithmArgon2id selects argon2id.
+	AlgorithmArgon2id
+)
+
+// ErrUnknownAlgorithm is returned when a hash's encoded prefix does not
+// match any algorithm known to this package.
+var ErrUnknownAlgorithm = errors.New("bcryptx: unknown hash algorithm")
+
+// Hasher abstracts a single password-hashing algorithm tuned for a "quick"
+// and a "strong" cost tier. Bcrypter delegates to a Hasher for all
+// algorithm-specific work so that callers can switch algorithms, or verify
+// a database of hashes produced by more than one, without changing their
+// call sites.
+type Hasher interface {
+	// GenerateQuick returns a hash produced using the tuned quick-tier
+	// cost, or any error encountered during handling.
+	GenerateQuick(pass string) (string, error)
+
+	// GenerateStrong returns a hash produced using the tuned strong-tier
+	// cost, or any error encountered during handling.
+	GenerateStrong(pass string) (string, error)
+
+	// Compare returns an error if hash and pass do not match, or any
+	// error encountered during handling.
+	Compare(hash, pass string) error
+
+	// Cost returns a value representing the relative cost encoded in
+	// hash, comparable against QuickCost and StrongCost.
+	Cost(hash string) (int, error)
+
+	// Tune calibrates the quick- and strong-tier cost parameters so that
+	// generating a hash takes as long as possible without exceeding
+	// quickMax and strongMax respectively.
+	Tune(quickMax, strongMax time.Duration) error
+
+	// QuickCost returns the cost set by the most recent Tune.
+	QuickCost() int
+
+	// StrongCost returns the cost set by the most recent Tune.
+	StrongCost() int
+
+	// TuneParams returns the raw tier parameters set by the most recent
+	// Tune, suitable for persisting in a TuneCache and later restoring
+	// via SetTuneParams without recalibrating.
+	TuneParams() (quick, strong []int)
+
+	// SetTuneParams restores tier parameters previously returned by
+	// TuneParams, bypassing calibration.
+	SetTuneParams(quick, strong []int)
+
+	// Prefix returns the encoded-hash prefix used to identify hashes
+	// produced by this Hasher, e.g. "$scrypt$".
+	Prefix() string
+
+	// Algorithm returns the Algorithm this Hasher implements.
+	Algorithm() Algorithm
+}
+
+// newHasher returns the Hasher for the given Algorithm.
+func newHasher(alg Algorithm, opts *Options) Hasher {
+	switch alg {
+	case AlgorithmScrypt:
+		return newScryptHasher(opts.MaxMemoryMiB)
+	case AlgorithmArgon2id:
+		return newArgon2idHasher(opts.MaxMemoryMiB)
+	default:
+		return newBcryptHasher()
+	}
+}
+
+// knownHashers returns one stateless instance of every Hasher
+// implementation, used to identify and verify a hash regardless of which
+// algorithm a particular Bcrypter is configured to generate with.
+func knownHashers() []Hasher {
+	return []Hasher{
+		newBcryptHasher(),
+		newScryptHasher(0),
+		newArgon2idHasher(0),
+	}
+}
+
+// hasherForHash returns the Hasher able to handle hash, based on its
+// encoded prefix.
+func hasherForHash(hash string) (Hasher, error) {
+	for _, h := range knownHashers() {
+		if strings.HasPrefix(hash, h.Prefix()) {
+			return h, nil
+		}
+	}
+	return nil, ErrUnknownAlgorithm
+}
@@ -0,0 +1,240 @@
+package bcryptx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	scryptPrefix = "$scrypt$"
+
+	scryptSaltLen = 16
+	scryptKeyLen  = 32
+	scryptR       = 8
+	scryptP       = 1
+	scryptMinN    = 1 << 10
+	scryptMaxN    = 1 << 30
+
+	// DefaultMaxMemoryMiB is used by scrypt and argon2id Hashers when
+	// Options.MaxMemoryMiB is unset.
+	DefaultMaxMemoryMiB = 64
+)
+
+// scryptHasher implements Hasher using golang.org/x/crypto/scrypt. Only N is
+// tuned; r and p are held fixed, which keeps the memory-cost formula
+// (128*N*r bytes) simple to bound against maxMemoryMiB.
+type scryptHasher struct {
+	mu           sync.RWMutex
+	maxMemoryMiB int
+	quickN       int
+	strongN      int
+}
+
+// newScryptHasher returns a scryptHasher bounded by maxMemoryMiB, or
+// DefaultMaxMemoryMiB if zero.
+func newScryptHasher(maxMemoryMiB int) *scryptHasher {
+	if maxMemoryMiB == 0 {
+		maxMemoryMiB = DefaultMaxMemoryMiB
+	}
+	return &scryptHasher{maxMemoryMiB: maxMemoryMiB}
+}
+
+// GenerateQuick returns a hash produced using the tuned quick-tier cost.
+func (h *scryptHasher) GenerateQuick(pass string) (string, error) {
+	h.mu.RLock()
+	n := h.quickN
+	h.mu.RUnlock()
+	return h.generate(pass, n)
+}
+
+// GenerateStrong returns a hash produced using the tuned strong-tier cost.
+func (h *scryptHasher) GenerateStrong(pass string) (string, error) {
+	h.mu.RLock()
+	n := h.strongN
+	h.mu.RUnlock()
+	return h.generate(pass, n)
+}
+
+func (h *scryptHasher) generate(pass string, n int) (string, error) {
+	salt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(pass), salt, n, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeScrypt(n, scryptR, scryptP, salt, key), nil
+}
+
+// Compare returns an error if hash and pass do not match.
+func (h *scryptHasher) Compare(hash, pass string) error {
+	n, r, p, salt, key, err := decodeScrypt(hash)
+	if err != nil {
+		return err
+	}
+
+	cmp, err := scrypt.Key([]byte(pass), salt, n, r, p, len(key))
+	if err != nil {
+		return err
+	}
+
+	if !constantTimeEqual(cmp, key) {
+		return errors.New("bcryptx: scrypt hashedPassword does not match password")
+	}
+	return nil
+}
+
+// Cost returns log2(N) as encoded in hash.
+func (h *scryptHasher) Cost(hash string) (int, error) {
+	n, _, _, _, _, err := decodeScrypt(hash)
+	if err != nil {
+		return 0, err
+	}
+	return log2(n), nil
+}
+
+// QuickCost returns log2(N) as set by the most recent Tune.
+func (h *scryptHasher) QuickCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return log2(h.quickN)
+}
+
+// StrongCost returns log2(N) as set by the most recent Tune.
+func (h *scryptHasher) StrongCost() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return log2(h.strongN)
+}
+
+// TuneParams returns the tuned N as a single-element slice for each tier.
+func (h *scryptHasher) TuneParams() (quick, strong []int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return []int{h.quickN}, []int{h.strongN}
+}
+
+// SetTuneParams restores quickN and strongN from quick[0] and strong[0].
+func (h *scryptHasher) SetTuneParams(quick, strong []int) {
+	h.mu.Lock()
+	h.quickN = quick[0]
+	h.strongN = strong[0]
+	h.mu.Unlock()
+}
+
+// Prefix returns the encoded-hash prefix used by this package's scrypt
+// encoding.
+func (h *scryptHasher) Prefix() string {
+	return scryptPrefix
+}
+
+// Algorithm returns AlgorithmScrypt.
+func (h *scryptHasher) Algorithm() Algorithm {
+	return AlgorithmScrypt
+}
+
+// Tune sets quickN and strongN based on the provided max times, doubling N
+// from scryptMinN until the measured hash time exceeds each max time or
+// another doubling would exceed maxMemoryMiB, whichever comes first.
+func (h *scryptHasher) Tune(quickMax, strongMax time.Duration) error {
+	maxN := h.maxMemoryMiB * 1024 * 1024 / (128 * scryptR)
+	if maxN > scryptMaxN {
+		maxN = scryptMaxN
+	}
+
+	var qn, sn int
+	n := scryptMinN
+	for {
+		t1 := time.Now()
+		if _, err := scrypt.Key([]byte(testStr), []byte(testStr), n, scryptR, scryptP, scryptKeyLen); err != nil {
+			return err
+		}
+		d := time.Since(t1)
+
+		if qn == 0 && d > quickMax {
+			qn = n / 2
+		}
+		if sn == 0 && d > strongMax {
+			sn = n / 2
+		}
+
+		if sn != 0 || n*2 > maxN {
+			break
+		}
+		n *= 2
+	}
+
+	if qn == 0 {
+		qn = n
+	}
+	if sn == 0 {
+		sn = n
+	}
+	if qn < scryptMinN {
+		qn = scryptMinN
+	}
+	if sn < scryptMinN {
+		sn = scryptMinN
+	}
+
+	h.mu.Lock()
+	h.quickN = qn
+	h.strongN = sn
+	h.mu.Unlock()
+
+	return nil
+}
+
+func encodeScrypt(n, r, p int, salt, key []byte) string {
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s%d$%d$%d$%s$%s", scryptPrefix, n, r, p,
+		enc.EncodeToString(salt), enc.EncodeToString(key))
+}
+
+func decodeScrypt(hash string) (n, r, p int, salt, key []byte, err error) {
+	if !strings.HasPrefix(hash, scryptPrefix) {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	parts := strings.Split(strings.TrimPrefix(hash, scryptPrefix), "$")
+	if len(parts) != 5 {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	n, errN := strconv.Atoi(parts[0])
+	r, errR := strconv.Atoi(parts[1])
+	p, errP := strconv.Atoi(parts[2])
+	if errN != nil || errR != nil || errP != nil {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	enc := base64.RawStdEncoding
+	salt, errSalt := enc.DecodeString(parts[3])
+	key, errKey := enc.DecodeString(parts[4])
+	if errSalt != nil || errKey != nil {
+		return 0, 0, 0, nil, nil, ErrMalformed
+	}
+
+	return n, r, p, salt, key, nil
+}
+
+// log2 returns the base-2 logarithm of n, rounded down, for n > 0.
+func log2(n int) int {
+	var l int
+	for n > 1 {
+		n >>= 1
+		l++
+	}
+	return l
+}
@@ -0,0 +1,86 @@
+package bcryptx
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TierStats reports the tuned cost and observed Gen*FromPass durations for
+// one cost tier. Samples, Mean, and P95 are zero unless Options.AdaptiveRetune
+// is enabled.
+type TierStats struct {
+	Cost    int
+	Samples int
+	Mean    time.Duration
+	P95     time.Duration
+}
+
+// tierStats is a fixed-size ring buffer of observed hash durations for one
+// cost tier, used to drive AdaptiveRetune.
+type tierStats struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	count   int
+}
+
+// newTierStats returns a tierStats with room for window samples.
+func newTierStats(window int) *tierStats {
+	return &tierStats{samples: make([]time.Duration, window)}
+}
+
+// record appends d to the ring buffer, overwriting the oldest sample once
+// full.
+func (s *tierStats) record(d time.Duration) {
+	s.mu.Lock()
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % len(s.samples)
+	if s.count < len(s.samples) {
+		s.count++
+	}
+	s.mu.Unlock()
+}
+
+// full reports whether the ring buffer holds a full window of samples.
+func (s *tierStats) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count == len(s.samples)
+}
+
+// reset discards all recorded samples.
+func (s *tierStats) reset() {
+	s.mu.Lock()
+	s.next, s.count = 0, 0
+	s.mu.Unlock()
+}
+
+// meanP95 returns the mean and 95th-percentile duration across recorded
+// samples, and the sample count.
+func (s *tierStats) meanP95() (mean, p95 time.Duration, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == 0 {
+		return 0, 0, 0
+	}
+
+	vals := make([]time.Duration, s.count)
+	copy(vals, s.samples[:s.count])
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+
+	var sum time.Duration
+	for _, v := range vals {
+		sum += v
+	}
+	mean = sum / time.Duration(len(vals))
+
+	idx := int(float64(len(vals)) * 0.95)
+	if idx >= len(vals) {
+		idx = len(vals) - 1
+	}
+	p95 = vals[idx]
+
+	return mean, p95, s.count
+}
@@ -0,0 +1,167 @@
+package bcryptx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TuneCacheEntry holds the raw tier parameters produced by a Hasher's Tune,
+// as returned by Hasher.TuneParams and restored via Hasher.SetTuneParams.
+type TuneCacheEntry struct {
+	Quick  []int
+	Strong []int
+}
+
+// TuneCache persists tuned costs so that New followed by Tune can skip
+// recalibration on a host it has already tuned for. Implementations must be
+// safe for concurrent use.
+type TuneCache interface {
+	// Load returns the entry stored under key, and whether one was found.
+	Load(key string) (TuneCacheEntry, bool)
+
+	// Store saves entry under key.
+	Store(key string, entry TuneCacheEntry) error
+
+	// Delete removes any entry stored under key.
+	Delete(key string) error
+}
+
+// fileTuneCache is the TuneCache used when Options.TuneCachePath is set
+// without an explicit Options.TuneCache. Entries are stored as JSON,
+// keyed by fingerprint, in a single file.
+type fileTuneCache struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newFileTuneCache returns a TuneCache backed by the JSON file at path.
+func newFileTuneCache(path string) *fileTuneCache {
+	return &fileTuneCache{path: path}
+}
+
+func (c *fileTuneCache) Load(key string) (TuneCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		return TuneCacheEntry{}, false
+	}
+
+	e, ok := entries[key]
+	return e, ok
+}
+
+func (c *fileTuneCache) Store(key string, entry TuneCacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		entries = map[string]TuneCacheEntry{}
+	}
+	entries[key] = entry
+
+	return c.write(entries)
+}
+
+func (c *fileTuneCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		return nil
+	}
+	delete(entries, key)
+
+	return c.write(entries)
+}
+
+func (c *fileTuneCache) read() (map[string]TuneCacheEntry, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]TuneCacheEntry{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (c *fileTuneCache) write(entries map[string]TuneCacheEntry) error {
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// fingerprint identifies a host and tuning configuration, so that cached
+// costs are only reused where they were calibrated for: GOARCH,
+// GOMAXPROCS, a CPU model string, the configured Algorithm, the configured
+// max times, and MaxMemoryMiB (since it bounds what Tune calibrates for
+// AlgorithmScrypt and AlgorithmArgon2id).
+func fingerprint(opts *Options) string {
+	parts := []string{
+		runtime.GOARCH,
+		strconv.Itoa(runtime.GOMAXPROCS(0)),
+		cpuModel(),
+		strconv.Itoa(int(opts.Algorithm)),
+		opts.GenQuickMaxTime.String(),
+		opts.GenStrongMaxTime.String(),
+		strconv.Itoa(opts.MaxMemoryMiB),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// altFingerprint is fingerprint for alg instead of opts.Algorithm, used to
+// key the tune cache entry for a Bcrypter's lazily-tuned alternate Hashers
+// (see Bcrypter.quickCostFor), so they share the same persistent cache as
+// the primary Hasher without colliding with its entry.
+func altFingerprint(opts *Options, alg Algorithm) string {
+	altOpts := *opts
+	altOpts.Algorithm = alg
+	return fingerprint(&altOpts)
+}
+
+// cpuModel returns a CPU model string for the current host, or "" if one
+// can't be determined.
+func cpuModel() string {
+	if b, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if !strings.HasPrefix(line, "model name") {
+				continue
+			}
+			if i := strings.Index(line, ":"); i >= 0 {
+				return strings.TrimSpace(line[i+1:])
+			}
+		}
+		return ""
+	}
+
+	if runtime.GOOS == "darwin" {
+		out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+
+	return ""
+}
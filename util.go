@@ -0,0 +1,9 @@
+package bcryptx
+
+import "crypto/subtle"
+
+// constantTimeEqual reports whether a and b are equal, in constant time
+// with respect to their contents.
+func constantTimeEqual(a, b []byte) bool {
+	return len(a) == len(b) && subtle.ConstantTimeCompare(a, b) == 1
+}